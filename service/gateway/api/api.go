@@ -2,6 +2,7 @@
 package api
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/http"
 
@@ -28,9 +29,17 @@ import (
 	"github.com/stack-labs/stack-rpc/util/log"
 
 	"github.com/stack-labs/stack-rpc-plugins/service/gateway/handler"
+	"github.com/stack-labs/stack-rpc-plugins/service/gateway/handler/grpcweb"
 	"github.com/stack-labs/stack-rpc-plugins/service/gateway/helper"
+	"github.com/stack-labs/stack-rpc-plugins/service/gateway/mtls"
 	"github.com/stack-labs/stack-rpc-plugins/service/gateway/plugin"
+	_ "github.com/stack-labs/stack-rpc-plugins/service/gateway/plugin/auth"
+	_ "github.com/stack-labs/stack-rpc-plugins/service/gateway/plugin/breaker"
+	_ "github.com/stack-labs/stack-rpc-plugins/service/gateway/plugin/ratelimit"
+	tagResolver "github.com/stack-labs/stack-rpc-plugins/service/gateway/resolver/tag"
+	staticRouter "github.com/stack-labs/stack-rpc-plugins/service/gateway/router/static"
 	"github.com/stack-labs/stack-rpc-plugins/service/gateway/stats"
+	"github.com/stack-labs/stack-rpc-plugins/service/gateway/tracing"
 )
 
 // basic vars
@@ -39,6 +48,10 @@ var (
 	Address               = ":8080"
 	Handler               = "meta"
 	Resolver              = "stack"
+	Router                = "registry"
+	RouterConfig          = ""
+	TagRules              = ""
+	RequireClientCert     = false
 	RPCPath               = "/rpc"
 	APIPath               = "/"
 	ProxyPath             = "/{service:[a-zA-Z0-9]+}"
@@ -50,6 +63,42 @@ var (
 	ACMECA                = acme.LetsEncryptProductionCA
 )
 
+// newRouter builds the api/router.Router to use for a given handler mode.
+// By default routes are discovered from the service registry; with
+// --router=static they are instead loaded from a declarative route file,
+// so a gateway can be pinned to exact route->service mappings; the
+// backend nodes are still looked up in the registry.
+func newRouter(rr resolver.Resolver, service stack.Service, opts ...router.Option) router.Router {
+	ropts := append([]router.Option{
+		router.WithNamespace(Namespace),
+		router.WithResolver(rr),
+		router.WithRegistry(service.Options().Registry),
+	}, opts...)
+
+	var rt router.Router
+	switch Router {
+	case "static":
+		rt = staticRouter.NewRouter(RouterConfig, ropts...)
+	default:
+		rt = regRouter.NewRouter(ropts...)
+	}
+
+	// with --resolver=tag, narrow the resolved service down to the nodes
+	// matching the tag the resolver picked for this request - without
+	// this the tag resolver only ever decorates the request with a
+	// header that nothing downstream reads.
+	if Resolver == "tag" {
+		rt = tagResolver.NewRouter(rt, rr)
+	}
+
+	// record the service/endpoint this router resolves a request to so the
+	// tracing plugin can report it on the span, regardless of which
+	// resolver/router combination is active.
+	rt = tracing.NewRouter(rt)
+
+	return rt
+}
+
 // run api gateway
 func Run(ctx *cli.Context, service stack.Service) ([]stack.Option, error) {
 	if len(ctx.GlobalString("server_name")) > 0 {
@@ -67,6 +116,15 @@ func Run(ctx *cli.Context, service stack.Service) ([]stack.Option, error) {
 	if len(ctx.String("resolver")) > 0 {
 		Resolver = ctx.String("resolver")
 	}
+	if len(ctx.String("router")) > 0 {
+		Router = ctx.String("router")
+	}
+	if len(ctx.String("router_config")) > 0 {
+		RouterConfig = ctx.String("router_config")
+	}
+	if len(ctx.String("tag_rules")) > 0 {
+		TagRules = ctx.String("tag_rules")
+	}
 	if len(ctx.String("enable_rpc")) > 0 {
 		EnableRPC = ctx.Bool("enable_rpc")
 	}
@@ -99,6 +157,17 @@ func Run(ctx *cli.Context, service stack.Service) ([]stack.Option, error) {
 			return nil, err
 		}
 
+		if ctx.Bool("require_client_cert") {
+			RequireClientCert = true
+			pool, err := mtls.LoadClientCAs(ctx.String("client_ca_file"))
+			if err != nil {
+				fmt.Println(err.Error())
+				return nil, err
+			}
+			config.ClientCAs = pool
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+
 		opts = append(opts, server.EnableTLS(true))
 		opts = append(opts, server.TLSConfig(config))
 	}
@@ -108,10 +177,14 @@ func Run(ctx *cli.Context, service stack.Service) ([]stack.Option, error) {
 	r := mux.NewRouter()
 	h = r
 
+	if RequireClientCert {
+		h = mtls.NewMiddleware()(h)
+	}
+
 	if ctx.GlobalBool("enable_stats") {
 		st := stats.New()
 		r.HandleFunc("/stats", st.StatsHandler)
-		h = st.ServeHTTP(r)
+		h = st.ServeHTTP(h)
 		st.Start()
 		defer st.Stop()
 	}
@@ -164,31 +237,37 @@ func Run(ctx *cli.Context, service stack.Service) ([]stack.Option, error) {
 		rr = path.NewResolver(ropts...)
 	case "grpc":
 		rr = grpc.NewResolver(ropts...)
+	case "tag":
+		rr = tagResolver.NewResolver(TagRules, ropts...)
 	}
 
 	switch Handler {
 	case "rpc":
 		log.Logf("Registering API RPC Handler at %s", APIPath)
-		rt := regRouter.NewRouter(
-			router.WithNamespace(Namespace),
-			router.WithHandler(arpc.Handler),
-			router.WithResolver(rr),
-			router.WithRegistry(service.Options().Registry),
-		)
+		rt := newRouter(rr, service, router.WithHandler(arpc.Handler))
 		rp := arpc.NewHandler(
 			ahandler.WithNamespace(Namespace),
 			ahandler.WithRouter(rt),
 			ahandler.WithService(service),
 		)
 		r.PathPrefix(APIPath).Handler(rp)
+	case "grpcweb":
+		log.Logf("Registering API gRPC-Web Handler at %s", APIPath)
+		rt := newRouter(rr, service, router.WithHandler(arpc.Handler))
+		rp := arpc.NewHandler(
+			ahandler.WithNamespace(Namespace),
+			ahandler.WithRouter(rt),
+			ahandler.WithService(service),
+		)
+		gw := grpcweb.NewHandler(rp,
+			ahandler.WithNamespace(Namespace),
+			ahandler.WithRouter(rt),
+			ahandler.WithService(service),
+		)
+		r.PathPrefix(APIPath).Handler(gw)
 	case "api":
 		log.Logf("Registering API Request Handler at %s", APIPath)
-		rt := regRouter.NewRouter(
-			router.WithNamespace(Namespace),
-			router.WithHandler(aapi.Handler),
-			router.WithResolver(rr),
-			router.WithRegistry(service.Options().Registry),
-		)
+		rt := newRouter(rr, service, router.WithHandler(aapi.Handler))
 		ap := aapi.NewHandler(
 			ahandler.WithNamespace(Namespace),
 			ahandler.WithRouter(rt),
@@ -197,12 +276,7 @@ func Run(ctx *cli.Context, service stack.Service) ([]stack.Option, error) {
 		r.PathPrefix(APIPath).Handler(ap)
 	case "event":
 		log.Logf("Registering API Event Handler at %s", APIPath)
-		rt := regRouter.NewRouter(
-			router.WithNamespace(Namespace),
-			router.WithHandler(event.Handler),
-			router.WithResolver(rr),
-			router.WithRegistry(service.Options().Registry),
-		)
+		rt := newRouter(rr, service, router.WithHandler(event.Handler))
 		ev := event.NewHandler(
 			ahandler.WithNamespace(Namespace),
 			ahandler.WithRouter(rt),
@@ -211,12 +285,7 @@ func Run(ctx *cli.Context, service stack.Service) ([]stack.Option, error) {
 		r.PathPrefix(APIPath).Handler(ev)
 	case "http", "proxy":
 		log.Logf("Registering API HTTP Handler at %s", ProxyPath)
-		rt := regRouter.NewRouter(
-			router.WithNamespace(Namespace),
-			router.WithHandler(ahttp.Handler),
-			router.WithResolver(rr),
-			router.WithRegistry(service.Options().Registry),
-		)
+		rt := newRouter(rr, service, router.WithHandler(ahttp.Handler))
 		ht := ahttp.NewHandler(
 			ahandler.WithNamespace(Namespace),
 			ahandler.WithRouter(rt),
@@ -225,12 +294,7 @@ func Run(ctx *cli.Context, service stack.Service) ([]stack.Option, error) {
 		r.PathPrefix(ProxyPath).Handler(ht)
 	case "web":
 		log.Logf("Registering API Web Handler at %s", APIPath)
-		rt := regRouter.NewRouter(
-			router.WithNamespace(Namespace),
-			router.WithHandler(web.Handler),
-			router.WithResolver(rr),
-			router.WithRegistry(service.Options().Registry),
-		)
+		rt := newRouter(rr, service, router.WithHandler(web.Handler))
 		w := web.NewHandler(
 			ahandler.WithNamespace(Namespace),
 			ahandler.WithRouter(rt),
@@ -239,11 +303,7 @@ func Run(ctx *cli.Context, service stack.Service) ([]stack.Option, error) {
 		r.PathPrefix(APIPath).Handler(w)
 	default:
 		log.Logf("Registering API Default Handler at %s", APIPath)
-		rt := regRouter.NewRouter(
-			router.WithNamespace(Namespace),
-			router.WithResolver(rr),
-			router.WithRegistry(service.Options().Registry),
-		)
+		rt := newRouter(rr, service)
 		r.PathPrefix(APIPath).Handler(handler.Meta(service, rt))
 	}
 
@@ -282,7 +342,7 @@ func Options() (options []stack.Option) {
 		},
 		cli.StringFlag{
 			Name:   "handler",
-			Usage:  "Specify the request handler to be used for mapping HTTP requests to services; {api, event, http, rpc}",
+			Usage:  "Specify the request handler to be used for mapping HTTP requests to services; {api, event, grpcweb, http, rpc}",
 			EnvVar: "MICRO_API_HANDLER",
 		},
 		cli.StringFlag{
@@ -292,7 +352,7 @@ func Options() (options []stack.Option) {
 		},
 		cli.StringFlag{
 			Name:   "resolver",
-			Usage:  "Set the hostname resolver used by the API {host, path, grpc}",
+			Usage:  "Set the hostname resolver used by the API {host, path, grpc, tag}",
 			EnvVar: "MICRO_API_RESOLVER",
 		},
 		cli.BoolFlag{
@@ -300,6 +360,39 @@ func Options() (options []stack.Option) {
 			Usage:  "Enable call the backend directly via /rpc",
 			EnvVar: "MICRO_API_ENABLE_RPC",
 		},
+		cli.StringFlag{
+			Name:   "router",
+			Usage:  "Set the api router used to resolve requests to backends {registry, static}",
+			EnvVar: "MICRO_API_ROUTER",
+		},
+		cli.StringFlag{
+			Name:   "router_config",
+			Usage:  "Path to the route table used by --router=static e.g /etc/gateway/routes.yaml",
+			EnvVar: "MICRO_API_ROUTER_CONFIG",
+		},
+		cli.StringFlag{
+			Name:   "tag_rules",
+			Usage:  "Path to the canary/A-B tag policy used by --resolver=tag e.g /etc/gateway/tag_rules.yaml",
+			EnvVar: "MICRO_API_TAG_RULES",
+		},
+		cli.BoolFlag{
+			Name:   "require_client_cert",
+			Usage:  "Require and verify a client certificate when --enable_tls is set",
+			EnvVar: "MICRO_API_REQUIRE_CLIENT_CERT",
+		},
+		cli.StringFlag{
+			Name:   "client_ca_file",
+			Usage:  "Path to the PEM CA bundle used to verify client certificates",
+			EnvVar: "MICRO_API_CLIENT_CA_FILE",
+		},
+	}
+
+	// plugins (rate limiting, circuit breaking, ...) contribute their own
+	// flags rather than having every knob declared here.
+	for _, p := range plugin.Plugins() {
+		if fp, ok := p.(interface{ Flags() []cli.Flag }); ok {
+			flags = append(flags, fp.Flags()...)
+		}
 	}
 
 	options = append(options, stack.Flags(flags...))
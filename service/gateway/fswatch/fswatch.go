@@ -0,0 +1,69 @@
+// Package fswatch hot-reloads a single config file, surviving atomic
+// replacement. fsnotify documents that watching a file directly does not
+// survive it being moved to another path - and an atomic replace (the
+// standard way editors, `kubectl cp`, and ConfigMap symlink swaps update
+// a file) does exactly that, silently ending the watch on the first such
+// edit. Watching the parent directory and filtering by base name avoids
+// the problem.
+package fswatch
+
+import (
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher calls onChange whenever the file at path is written, created,
+// or renamed into place, and onError on any watch error.
+type Watcher struct {
+	path string
+	fw   *fsnotify.Watcher
+}
+
+// New starts watching path's parent directory and returns once the
+// watch is established.
+func New(path string, onChange func(), onError func(error)) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := fw.Add(filepath.Dir(path)); err != nil {
+		fw.Close()
+		return nil, err
+	}
+
+	w := &Watcher{path: path, fw: fw}
+	go w.run(onChange, onError)
+	return w, nil
+}
+
+func (w *Watcher) run(onChange func(), onError func(error)) {
+	base := filepath.Base(w.path)
+	for {
+		select {
+		case ev, ok := <-w.fw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Base(ev.Name) != base {
+				continue
+			}
+			// editors frequently replace the file rather than writing in
+			// place, so react to both Write and Create/Rename events.
+			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			onChange()
+		case err, ok := <-w.fw.Errors:
+			if !ok {
+				return
+			}
+			onError(err)
+		}
+	}
+}
+
+// Close stops the watch.
+func (w *Watcher) Close() error {
+	return w.fw.Close()
+}
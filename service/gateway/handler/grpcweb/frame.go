@@ -0,0 +1,164 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// grpc-web frames are a 1 byte flag followed by a 4 byte big endian
+// length and the payload, identical in shape to the length-prefixed
+// framing gRPC itself uses, just without the HTTP/2 trailers frame.
+const (
+	flagData    byte = 0x00
+	flagTrailer byte = 0x80
+)
+
+// readFrames unframes every message in body, returning them in order. A
+// grpc-web request is almost always a single unary message, but the
+// format allows more than one.
+func readFrames(body io.Reader, base64Encoded bool) ([][]byte, error) {
+	r := body
+	if base64Encoded {
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		r = base64.NewDecoder(base64.StdEncoding, bytes.NewReader(b))
+	}
+
+	var msgs [][]byte
+	var hdr [5]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		n := binary.BigEndian.Uint32(hdr[1:])
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// frameWriter wraps an http.ResponseWriter, framing every Write as a
+// grpc-web data frame and emitting the grpc-status/grpc-message trailer
+// as a final trailer frame once the handler is done - browsers cannot
+// read HTTP/2 trailers, so grpc-web folds them into the body instead.
+type frameWriter struct {
+	http.ResponseWriter
+	base64Encoded bool
+	wroteHeader   bool
+	status        int
+
+	// enc is the base64 encoder every frame is written through when
+	// base64Encoded, shared for the lifetime of the response: base64
+	// padding is only valid once for the whole stream, not once per
+	// frame, so it must not be Closed until the last frame is written.
+	enc io.WriteCloser
+}
+
+func newFrameWriter(w http.ResponseWriter, base64Encoded bool) *frameWriter {
+	return &frameWriter{ResponseWriter: w, base64Encoded: base64Encoded}
+}
+
+func (fw *frameWriter) Write(data []byte) (int, error) {
+	if !fw.wroteHeader {
+		fw.WriteHeader(http.StatusOK)
+	}
+	if err := fw.writeFrame(flagData, data); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (fw *frameWriter) WriteHeader(code int) {
+	if fw.wroteHeader {
+		return
+	}
+	fw.wroteHeader = true
+	fw.status = code
+	fw.ResponseWriter.Header().Set("Content-Type", fw.contentType())
+	fw.ResponseWriter.WriteHeader(code)
+}
+
+func (fw *frameWriter) contentType() string {
+	if fw.base64Encoded {
+		return "application/grpc-web-text+proto"
+	}
+	return "application/grpc-web+proto"
+}
+
+// Trailer flushes the grpc-status/grpc-message (and any other grpc
+// metadata) as a final trailer frame, ending the response.
+func (fw *frameWriter) Trailer(status int, message string, extra http.Header) error {
+	var buf bytes.Buffer
+	buf.WriteString("grpc-status: ")
+	buf.WriteString(strconv.Itoa(status))
+	buf.WriteString("\r\n")
+	if len(message) > 0 {
+		buf.WriteString("grpc-message: ")
+		buf.WriteString(message)
+		buf.WriteString("\r\n")
+	}
+	for k, vs := range extra {
+		for _, v := range vs {
+			buf.WriteString(k)
+			buf.WriteString(": ")
+			buf.WriteString(v)
+			buf.WriteString("\r\n")
+		}
+	}
+	return fw.writeFrame(flagTrailer, buf.Bytes())
+}
+
+func (fw *frameWriter) writeFrame(flag byte, data []byte) error {
+	var hdr [5]byte
+	hdr[0] = flag
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(data)))
+
+	out := fw.frameOutput()
+	if _, err := out.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := out.Write(data)
+	return err
+}
+
+// frameOutput returns the writer frames are written to: the raw
+// ResponseWriter when unencoded, or a single base64 encoder shared
+// across every frame in the response when grpc-web-text.
+func (fw *frameWriter) frameOutput() io.Writer {
+	if !fw.base64Encoded {
+		return fw.ResponseWriter
+	}
+	if fw.enc == nil {
+		fw.enc = base64.NewEncoder(base64.StdEncoding, fw.ResponseWriter)
+	}
+	return fw.enc
+}
+
+func (fw *frameWriter) Flush() {
+	if f, ok := fw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close flushes any base64 output buffered across writeFrame calls. It
+// must be called once the response, including the trailer frame, has
+// been fully written - a no-op for unencoded (binary grpc-web) responses.
+func (fw *frameWriter) Close() error {
+	if fw.enc == nil {
+		return nil
+	}
+	return fw.enc.Close()
+}
@@ -0,0 +1,155 @@
+// Package grpcweb bridges browser and plain REST/JSON clients onto
+// backend stack-rpc services without a sidecar. It wraps an inner
+// handler (normally api/handler/rpc) with two transcodings:
+//
+//   - grpc-web / grpc-web-text: unframes the length-prefixed message(s)
+//     from the request body and reframes the response and trailers the
+//     same way, so a grpc-web JS client can talk straight to the gateway.
+//   - plain HTTP/JSON: matches the request against `google.api.http`
+//     style rules attached to backend endpoints as registry metadata,
+//     merges path/query parameters into the JSON body, and dispatches
+//     straight to the matched rule's backend endpoint. Requests that
+//     match no rule are forwarded to the inner handler unchanged.
+package grpcweb
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/stack-labs/stack-rpc/api"
+	ahandler "github.com/stack-labs/stack-rpc/api/handler"
+	arpc "github.com/stack-labs/stack-rpc/api/handler/rpc"
+	"github.com/stack-labs/stack-rpc/util/log"
+)
+
+// Handler is the name used to select this mode via --handler=grpcweb.
+const Handler = "grpcweb"
+
+var errNoRule = errors.New("grpcweb: no matching http rule")
+
+type grpcWebHandler struct {
+	opts  ahandler.Options
+	next  http.Handler
+	rules *ruleTable
+}
+
+// NewHandler returns a handler that performs grpc-web/HTTP-JSON
+// transcoding in front of next, which dispatches grpc-web requests and
+// JSON requests that match no http rule to the backend service (normally
+// api/handler/rpc.NewHandler).
+func NewHandler(next http.Handler, opts ...ahandler.Option) http.Handler {
+	options := ahandler.NewOptions(opts...)
+
+	rt := newRuleTable(options.Namespace)
+	if options.Service != nil {
+		reg := options.Service.Options().Registry
+		if err := rt.Refresh(reg); err != nil {
+			log.Logf("grpcweb: initial rule refresh failed: %v", err)
+		}
+		go rt.watch(reg)
+	}
+
+	return &grpcWebHandler{
+		opts:  options,
+		next:  next,
+		rules: rt,
+	}
+}
+
+func (h *grpcWebHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ct := r.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(ct, "application/grpc-web-text"):
+		h.serveGRPCWeb(w, r, true)
+	case strings.HasPrefix(ct, "application/grpc-web"):
+		h.serveGRPCWeb(w, r, false)
+	default:
+		h.serveHTTPRule(w, r)
+	}
+}
+
+// serveGRPCWeb unframes the grpc-web request body and forwards the raw
+// message to the inner handler, then reframes whatever it wrote (plus a
+// trailer frame carrying grpc-status/grpc-message) back to the client.
+func (h *grpcWebHandler) serveGRPCWeb(w http.ResponseWriter, r *http.Request, base64Encoded bool) {
+	msgs, err := readFrames(r.Body, base64Encoded)
+	if err != nil {
+		http.Error(w, "grpcweb: malformed request frame", http.StatusBadRequest)
+		return
+	}
+	if len(msgs) == 0 {
+		http.Error(w, "grpcweb: empty request", http.StatusBadRequest)
+		return
+	}
+
+	// grpc-web is almost always unary; forward the single message as-is.
+	r.Body = ioutil.NopCloser(bytes.NewReader(msgs[0]))
+	r.ContentLength = int64(len(msgs[0]))
+
+	fw := newFrameWriter(w, base64Encoded)
+	h.next.ServeHTTP(fw, r)
+
+	// 0 is grpc's OK status, 2 is UNKNOWN - the inner handler speaks HTTP
+	// status codes, not grpc ones, so any error response is folded to
+	// UNKNOWN rather than guessing a more specific grpc code. Read the
+	// status frameWriter itself recorded rather than probing w, which may
+	// be wrapped by other middleware (tracing, breaker) that doesn't
+	// promote a Status() method.
+	status, message := 0, ""
+	if fw.status >= http.StatusBadRequest {
+		status, message = 2, http.StatusText(fw.status)
+	}
+	if err := fw.Trailer(status, message, nil); err != nil {
+		log.Logf("grpcweb: failed writing trailer: %v", err)
+	}
+	if err := fw.Close(); err != nil {
+		log.Logf("grpcweb: failed flushing response: %v", err)
+	}
+}
+
+// serveHTTPRule matches r against the known http rules and, on a match,
+// merges path/query params into the body and dispatches the call to the
+// rule's service/endpoint. Without a match it delegates to the inner
+// handler, which resolves and dispatches the call as usual.
+func (h *grpcWebHandler) serveHTTPRule(w http.ResponseWriter, r *http.Request) {
+	rl, params, err := h.rules.match(r)
+	if err == errNoRule {
+		// fall through - let the inner handler apply its own resolver
+		// based matching (e.g. namespace.service/endpoint path convention).
+		h.next.ServeHTTP(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	raw, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "grpcweb: failed reading request body", http.StatusBadRequest)
+		return
+	}
+
+	body, err := mergeBody(rl, raw, params)
+	if err != nil {
+		http.Error(w, "grpcweb: failed merging request params", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	// dispatch straight to the service/endpoint the rule already resolved
+	// rather than falling through to h.next, which would otherwise
+	// re-resolve the same URL through its router using the unrelated
+	// method/path/host metadata convention (api.Decode) and only work by
+	// coincidence if the backend also registered that convention.
+	arpc.WithService(&api.Service{
+		Name:     rl.Service,
+		Endpoint: &api.Endpoint{Name: rl.Endpoint},
+		Services: rl.services,
+	}, ahandler.WithNamespace(h.opts.Namespace), ahandler.WithService(h.opts.Service)).ServeHTTP(w, r)
+}
@@ -0,0 +1,241 @@
+package grpcweb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/stack-labs/stack-rpc/registry"
+	"github.com/stack-labs/stack-rpc/util/log"
+)
+
+// rule is a single google.api.http style HTTP mapping, attached to a
+// backend endpoint as registry metadata by the service that owns it
+// (e.g. "http.method", "http.path", "http.body" set via the codegen'd
+// grpc-gateway annotations).
+type rule struct {
+	Service  string
+	Endpoint string
+	Method   string
+	Path     string
+	Body     string // "*", "", or a field name - the google.api.http `body` selector
+
+	vars    []string
+	pattern *regexp.Regexp
+
+	// services is the resolved registry.Service set (all versions/nodes)
+	// for Service, captured at refresh time so a match can dispatch
+	// straight to arpc.WithService without a second registry lookup.
+	services []*registry.Service
+}
+
+// ruleTable resolves an inbound JSON/REST request to the backend
+// endpoint whose http rule matches it.
+type ruleTable struct {
+	namespace string
+	rules     []*rule
+}
+
+func newRuleTable(namespace string) *ruleTable {
+	return &ruleTable{namespace: namespace}
+}
+
+// watch rebuilds the rule table whenever the registry reports a change,
+// so a backend that registers (or updates its http.* endpoint metadata)
+// after the gateway starts is picked up without a restart. It never
+// returns; call it in its own goroutine.
+func (rt *ruleTable) watch(reg registry.Registry) {
+	var attempts int
+	for {
+		w, err := reg.Watch()
+		if err != nil {
+			attempts++
+			log.Logf("grpcweb: registry watch error: %v", err)
+			time.Sleep(time.Duration(attempts) * time.Second)
+			continue
+		}
+		attempts = 0
+
+		for {
+			if _, err := w.Next(); err != nil {
+				log.Logf("grpcweb: registry watch error: %v", err)
+				break
+			}
+			if err := rt.Refresh(reg); err != nil {
+				log.Logf("grpcweb: rule refresh failed: %v", err)
+			}
+		}
+		w.Stop()
+	}
+}
+
+// Refresh rebuilds the rule set from the current registry snapshot.
+func (rt *ruleTable) Refresh(reg registry.Registry) error {
+	services, err := reg.ListServices()
+	if err != nil {
+		return err
+	}
+
+	var rules []*rule
+	for _, svc := range services {
+		if !strings.HasPrefix(svc.Name, rt.namespace) {
+			continue
+		}
+
+		full, err := reg.GetService(svc.Name)
+		if err != nil {
+			continue
+		}
+		for _, s := range full {
+			for _, ep := range s.Endpoints {
+				r := ruleFromEndpoint(s.Name, ep)
+				if r != nil {
+					r.services = full
+					rules = append(rules, r)
+				}
+			}
+		}
+	}
+
+	rt.rules = rules
+	return nil
+}
+
+func ruleFromEndpoint(service string, ep *registry.Endpoint) *rule {
+	if ep.Metadata == nil {
+		return nil
+	}
+	path := ep.Metadata["http.path"]
+	if len(path) == 0 {
+		return nil
+	}
+	method := ep.Metadata["http.method"]
+	if len(method) == 0 {
+		method = http.MethodPost
+	}
+
+	r := &rule{
+		Service:  service,
+		Endpoint: ep.Name,
+		Method:   method,
+		Path:     path,
+		Body:     ep.Metadata["http.body"],
+	}
+	if err := r.compile(); err != nil {
+		return nil
+	}
+	return r
+}
+
+// compile turns a templated path ("/v1/users/{id}/posts/{post_id}") into
+// a matching regexp and records the variable names in declaration order.
+func (r *rule) compile() error {
+	var buf strings.Builder
+	buf.WriteString("^")
+
+	i := 0
+	for i < len(r.Path) {
+		c := r.Path[i]
+		if c == '{' {
+			end := strings.IndexByte(r.Path[i:], '}')
+			if end < 0 {
+				return fmt.Errorf("unterminated path variable in %q", r.Path)
+			}
+			name := r.Path[i+1 : i+end]
+			r.vars = append(r.vars, name)
+			buf.WriteString("(?P<")
+			buf.WriteString(regexp.MustCompile(`\W`).ReplaceAllString(name, "_"))
+			buf.WriteString(">[^/]+)")
+			i += end + 1
+			continue
+		}
+		buf.WriteString(regexp.QuoteMeta(string(c)))
+		i++
+	}
+	buf.WriteString("$")
+
+	pattern, err := regexp.Compile(buf.String())
+	if err != nil {
+		return err
+	}
+	r.pattern = pattern
+	return nil
+}
+
+// match returns the rule for r along with the path/query values to be
+// merged into the request body before dispatch.
+func (rt *ruleTable) match(req *http.Request) (*rule, map[string]interface{}, error) {
+	for _, r := range rt.rules {
+		if r.Method != req.Method {
+			continue
+		}
+		m := r.pattern.FindStringSubmatch(req.URL.Path)
+		if m == nil {
+			continue
+		}
+
+		params := make(map[string]interface{}, len(r.vars))
+		for i, name := range r.pattern.SubexpNames() {
+			if i == 0 || len(name) == 0 {
+				continue
+			}
+			params[name] = m[i]
+		}
+		for k, vs := range req.URL.Query() {
+			if len(vs) > 0 {
+				params[k] = vs[0]
+			}
+		}
+
+		return r, params, nil
+	}
+	return nil, nil, errNoRule
+}
+
+// mergeBody folds path/query params into the JSON request body per the
+// rule's `body` selector: "*" merges at the top level, a named field
+// nests the request body under that field with params as its top-level
+// siblings, and "" (GET/DELETE with no body) is built entirely from
+// params.
+func mergeBody(r *rule, raw []byte, params map[string]interface{}) ([]byte, error) {
+	switch r.Body {
+	case "":
+		return json.Marshal(params)
+	case "*":
+		var doc map[string]interface{}
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &doc); err != nil {
+				return nil, err
+			}
+		}
+		if doc == nil {
+			doc = map[string]interface{}{}
+		}
+		for k, v := range params {
+			doc[k] = v
+		}
+		return json.Marshal(doc)
+	default:
+		var body map[string]interface{}
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &body); err != nil {
+				return nil, err
+			}
+		}
+		if body == nil {
+			body = map[string]interface{}{}
+		}
+
+		// per google.api.http semantics, path/query params are top-level
+		// siblings of the named body submessage, not members of it.
+		doc := make(map[string]interface{}, len(params)+1)
+		for k, v := range params {
+			doc[k] = v
+		}
+		doc[r.Body] = body
+		return json.Marshal(doc)
+	}
+}
@@ -0,0 +1,61 @@
+// Package mtls loads a client CA bundle for --require_client_cert and
+// carries the verified client certificate's subject from the TLS
+// handshake into the request context, so later plugins (or backends, via
+// a forwarded header) can make decisions based on who presented a cert.
+package mtls
+
+import (
+	"context"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/stack-labs/stack-rpc/util/log"
+)
+
+// LoadClientCAs reads a PEM bundle of CA certificates used to verify
+// client certificates presented during the TLS handshake.
+func LoadClientCAs(path string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		log.Fatalf("mtls: no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+type subjectKey struct{}
+
+// subjectHeader mirrors the gateway's own HeaderPrefix convention
+// (api.HeaderPrefix) and the auth plugin's X-Stack-Auth-* headers for
+// forwarding verified identity to backends.
+const subjectHeader = "X-Stack-Auth-Cert-Subject"
+
+// Subject is the distinguished name of the verified client certificate.
+func Subject(ctx context.Context) (string, bool) {
+	s, ok := ctx.Value(subjectKey{}).(string)
+	return s, ok
+}
+
+// NewMiddleware populates the request context with the verified client
+// certificate's subject and forwards it to the backend as the
+// X-Stack-Auth-Cert-Subject header, same as the JWT/OIDC auth plugin
+// forwards verified claims. It should only be installed once
+// --require_client_cert has configured the server to require and verify
+// client certificates - otherwise there is nothing to extract.
+func NewMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+				subject := r.TLS.PeerCertificates[0].Subject.String()
+				r = r.WithContext(context.WithValue(r.Context(), subjectKey{}, subject))
+				r.Header.Set(subjectHeader, subject)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
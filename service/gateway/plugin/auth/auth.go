@@ -0,0 +1,121 @@
+// Package auth is a gateway plugin validating JWT bearer tokens against
+// an OIDC provider's published keys, enforcing per-route scope
+// requirements, and forwarding verified claims to the backend as
+// X-Stack-Auth-* headers so services don't each need their own OIDC
+// client.
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/stack-labs/stack-rpc/pkg/cli"
+	"github.com/stack-labs/stack-rpc/util/log"
+
+	"github.com/stack-labs/stack-rpc-plugins/service/gateway/plugin"
+)
+
+// authHeaderPrefix mirrors the gateway's own HeaderPrefix convention
+// (api.HeaderPrefix) for headers it forwards to backends.
+const authHeaderPrefix = "X-Stack-Auth-"
+
+func init() {
+	plugin.Register(newPlugin())
+}
+
+type authPlugin struct {
+	enabled  bool
+	audience string
+	policy   *Policy
+	keys     *keySet
+}
+
+func newPlugin() *authPlugin {
+	return &authPlugin{}
+}
+
+func (p *authPlugin) Flags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:   "enable_auth",
+			Usage:  "Enable JWT/OIDC authentication in front of backends",
+			EnvVar: "MICRO_API_ENABLE_AUTH",
+		},
+		cli.StringFlag{
+			Name:   "oidc_issuer",
+			Usage:  "OIDC issuer used to discover the JWKS endpoint",
+			EnvVar: "MICRO_API_OIDC_ISSUER",
+		},
+		cli.StringFlag{
+			Name:   "oidc_audience",
+			Usage:  "Expected `aud` claim on bearer tokens",
+			EnvVar: "MICRO_API_OIDC_AUDIENCE",
+		},
+		cli.StringFlag{
+			Name:   "auth_policy",
+			Usage:  "Path to the per-route auth policy e.g /etc/gateway/auth_policy.yaml",
+			EnvVar: "MICRO_API_AUTH_POLICY",
+		},
+	}
+}
+
+func (p *authPlugin) Init(ctx *cli.Context) {
+	p.enabled = ctx.GlobalBool("enable_auth")
+	if !p.enabled {
+		return
+	}
+
+	p.audience = ctx.GlobalString("oidc_audience")
+
+	policy, err := loadPolicy(ctx.GlobalString("auth_policy"))
+	if err != nil {
+		log.Fatalf("auth: failed to load policy: %v", err)
+	}
+	p.policy = policy
+
+	ks, err := newKeySet(ctx.GlobalString("oidc_issuer"), 15*time.Minute)
+	if err != nil {
+		log.Fatalf("auth: failed OIDC discovery: %v", err)
+	}
+	p.keys = ks
+}
+
+func (p *authPlugin) Handler() plugin.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !p.enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rp := p.policy.policyFor(r)
+
+			raw, ok := bearerToken(r)
+			if !ok {
+				if rp.AllowAnonymous {
+					next.ServeHTTP(w, r)
+					return
+				}
+				http.Error(w, "missing bearer token", http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := verify(raw, p.keys, p.audience)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if !hasScope(claims.Scope, rp.RequireScope) {
+				http.Error(w, "insufficient scope", http.StatusForbidden)
+				return
+			}
+
+			r.Header.Set(authHeaderPrefix+"Subject", claims.Subject)
+			r.Header.Set(authHeaderPrefix+"Scope", claims.Scope)
+			r.Header.Set(authHeaderPrefix+"Issuer", claims.Issuer)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
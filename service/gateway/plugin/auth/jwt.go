@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Claims is the subset of a validated bearer token's claims the gateway
+// cares about. Of these, the subject, scope and issuer are forwarded to
+// the backend as X-Stack-Auth-Subject, -Scope and -Issuer; no other
+// claims are forwarded.
+type Claims struct {
+	jwt.RegisteredClaims
+	Scope string `json:"scope"`
+}
+
+// bearerToken extracts the raw token from the Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(h, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(h, prefix), true
+}
+
+// verify parses and validates a bearer token against ks, the expected
+// audience and issuer.
+func verify(raw string, ks *keySet, audience string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(raw, claims, ks.keyFunc, jwt.WithValidMethods(rsaSigningMethods))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("auth: token failed validation")
+	}
+
+	if len(ks.issuer) > 0 && claims.Issuer != ks.issuer {
+		return nil, fmt.Errorf("auth: unexpected issuer %q", claims.Issuer)
+	}
+	if len(audience) > 0 && !containsAudience(claims.Audience, audience) {
+		return nil, fmt.Errorf("auth: token not valid for audience %q", audience)
+	}
+
+	return claims, nil
+}
+
+func containsAudience(aud jwt.ClaimStrings, want string) bool {
+	for _, a := range aud {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
+
+// rsaPublicKeyFromJWK decodes the RSA public key components of a JWK
+// (RFC 7517) into a usable *rsa.PublicKey.
+func rsaPublicKeyFromJWK(k jwk) (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
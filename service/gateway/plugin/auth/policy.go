@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Policy is the per-route auth requirement file: requests matching Path
+// must present a verified token carrying RequireScope, falling back to
+// Default when nothing matches. Path is either a filepath.Match glob
+// (matched against a single segment) or, with a trailing "/*", a prefix
+// match that also covers nested segments - "/v1/admin/*" protects
+// "/v1/admin/foo/bar", not just "/v1/admin/foo".
+type Policy struct {
+	Routes  []RoutePolicy `yaml:"routes"`
+	Default RoutePolicy   `yaml:"default"`
+}
+
+// RoutePolicy describes what a matching request must present to pass.
+type RoutePolicy struct {
+	Path           string `yaml:"path"`
+	RequireScope   string `yaml:"require_scope"`
+	AllowAnonymous bool   `yaml:"allow_anonymous"`
+}
+
+func loadPolicy(path string) (*Policy, error) {
+	if len(path) == 0 {
+		return &Policy{Default: RoutePolicy{AllowAnonymous: true}}, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := yaml.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// policyFor returns the first RoutePolicy, in file order, whose Path
+// matches r, or Default when none does.
+func (p *Policy) policyFor(r *http.Request) RoutePolicy {
+	for _, rp := range p.Routes {
+		if matchPath(rp.Path, r.URL.Path) {
+			return rp
+		}
+	}
+	return p.Default
+}
+
+// matchPath reports whether path satisfies pattern. A pattern ending in
+// "/*" matches path and anything nested below it, since filepath.Match's
+// "*" never crosses a "/" and would otherwise let nested routes fall
+// through to Default unprotected. Any other pattern is matched with
+// filepath.Match as before.
+func matchPath(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(path, strings.TrimSuffix(pattern, "*"))
+	}
+	ok, _ := filepath.Match(pattern, path)
+	return ok
+}
+
+// hasScope reports whether the space-separated scope claim contains
+// required, treating an empty requirement as always satisfied.
+func hasScope(scopeClaim, required string) bool {
+	if len(required) == 0 {
+		return true
+	}
+	for _, s := range strings.Fields(scopeClaim) {
+		if s == required {
+			return true
+		}
+	}
+	return false
+}
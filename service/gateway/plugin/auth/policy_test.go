@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPolicyForWildcardMatchesNestedPath(t *testing.T) {
+	p := &Policy{
+		Routes: []RoutePolicy{
+			{Path: "/v1/admin/*", RequireScope: "admin"},
+		},
+		Default: RoutePolicy{AllowAnonymous: true},
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/admin/foo/bar", nil)
+	rp := p.policyFor(r)
+
+	if rp.RequireScope != "admin" {
+		t.Fatalf("expected nested path under /v1/admin/* to require the admin scope, got policy %+v", rp)
+	}
+	if rp.AllowAnonymous {
+		t.Fatalf("nested admin path must not fall through to the anonymous-allowed default")
+	}
+}
@@ -0,0 +1,163 @@
+// Package breaker is a gateway plugin implementing a Hystrix-style
+// circuit breaker per method+path: it tracks a rolling error rate and
+// latency window, trips to an open state once either crosses its
+// configured threshold, and short-circuits requests to a configurable
+// fallback response until the breaker allows a trial request through
+// again.
+//
+// The breaker wraps the whole mux router ahead of service resolution
+// (same constraint as the rate limit plugin), so its key is the raw
+// inbound method+path rather than the resolved service+endpoint: a
+// templated route like "/v1/users/{id}" gets one circuit per id instead
+// of one shared circuit for the endpoint, and rarely accumulates enough
+// requests on any single key to trip.
+package breaker
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/stack-labs/stack-rpc/pkg/cli"
+	"github.com/stack-labs/stack-rpc/util/log"
+
+	"github.com/stack-labs/stack-rpc-plugins/service/gateway/plugin"
+)
+
+func init() {
+	plugin.Register(newPlugin())
+}
+
+// Config tunes when a breaker for a given key trips and how long it
+// stays open before allowing a half-open trial request through.
+type Config struct {
+	ErrorThreshold   float64       `yaml:"error_threshold"`   // e.g 0.5 == 50% errors
+	LatencyThreshold time.Duration `yaml:"latency_threshold"` // a request slower than this counts as an error
+	MinRequests      int           `yaml:"min_requests"`      // requests needed in a window before tripping
+	Window           time.Duration `yaml:"window"`
+	SleepWindow      time.Duration `yaml:"sleep_window"` // how long to stay open before a trial request
+	FallbackBody     string        `yaml:"fallback_body"`
+	FallbackStatus   int           `yaml:"fallback_status"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		ErrorThreshold:   0.5,
+		LatencyThreshold: 2 * time.Second,
+		MinRequests:      20,
+		Window:           10 * time.Second,
+		SleepWindow:      5 * time.Second,
+		FallbackStatus:   http.StatusServiceUnavailable,
+		FallbackBody:     `{"error":"service unavailable"}`,
+	}
+}
+
+// loadConfig starts from defaultConfig and overlays whatever fields path
+// sets, so an operator only needs to override e.g. fallback_body without
+// having to repeat every threshold.
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+	if len(path) == 0 {
+		return cfg, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+type breakerPlugin struct {
+	enabled bool
+	cfg     Config
+
+	sync.Mutex
+	breakers map[string]*circuit
+}
+
+func newPlugin() *breakerPlugin {
+	return &breakerPlugin{breakers: map[string]*circuit{}}
+}
+
+func (p *breakerPlugin) Flags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:   "enable_breaker",
+			Usage:  "Enable a circuit breaker in front of backends",
+			EnvVar: "MICRO_API_ENABLE_BREAKER",
+		},
+		cli.StringFlag{
+			Name:   "breaker_config",
+			Usage:  "Path to the circuit breaker config e.g /etc/gateway/breaker.yaml",
+			EnvVar: "MICRO_API_BREAKER_CONFIG",
+		},
+	}
+}
+
+func (p *breakerPlugin) Init(ctx *cli.Context) {
+	p.enabled = ctx.GlobalBool("enable_breaker")
+
+	cfg, err := loadConfig(ctx.GlobalString("breaker_config"))
+	if err != nil {
+		log.Fatalf("breaker: failed to load %s: %v", ctx.GlobalString("breaker_config"), err)
+	}
+	p.cfg = cfg
+}
+
+func (p *breakerPlugin) circuitFor(key string) *circuit {
+	p.Lock()
+	defer p.Unlock()
+
+	c, ok := p.breakers[key]
+	if !ok {
+		c = newCircuit(p.cfg)
+		p.breakers[key] = c
+	}
+	return c
+}
+
+func (p *breakerPlugin) Handler() plugin.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !p.enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// see the package doc: this is the raw inbound path, not a
+			// resolved endpoint, so templated routes fragment across many
+			// circuits instead of tripping one shared one.
+			key := r.Method + " " + r.URL.Path
+			c := p.circuitFor(key)
+
+			if !c.allow() {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(p.cfg.FallbackStatus)
+				w.Write([]byte(p.cfg.FallbackBody))
+				return
+			}
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rec, r)
+
+			c.record(rec.status < http.StatusInternalServerError, time.Since(start))
+		})
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,110 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+type state int
+
+const (
+	closedState state = iota
+	openState
+	halfOpenState
+)
+
+// circuit tracks a rolling window of outcomes for one key (the inbound
+// method+path, see the package doc) and trips open once both
+// MinRequests and ErrorThreshold are reached within Window.
+type circuit struct {
+	cfg Config
+
+	sync.Mutex
+	state     state
+	openedAt  time.Time
+	windowAt  time.Time
+	successes int
+	failures  int
+}
+
+func newCircuit(cfg Config) *circuit {
+	return &circuit{cfg: cfg, windowAt: time.Now()}
+}
+
+func (c *circuit) allow() bool {
+	c.Lock()
+	defer c.Unlock()
+
+	c.rollWindow()
+
+	switch c.state {
+	case openState:
+		if time.Since(c.openedAt) < c.cfg.SleepWindow {
+			return false
+		}
+		// sleep window elapsed - let a single trial request through.
+		c.state = halfOpenState
+		return true
+	case halfOpenState:
+		// the trial request is still in flight.
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *circuit) record(success bool, latency time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	if latency > c.cfg.LatencyThreshold {
+		success = false
+	}
+
+	if c.state == halfOpenState {
+		if success {
+			c.reset()
+		} else {
+			c.trip()
+		}
+		return
+	}
+
+	if success {
+		c.successes++
+	} else {
+		c.failures++
+	}
+
+	total := c.successes + c.failures
+	if total < c.cfg.MinRequests {
+		return
+	}
+	if float64(c.failures)/float64(total) >= c.cfg.ErrorThreshold {
+		c.trip()
+	}
+}
+
+func (c *circuit) trip() {
+	c.state = openState
+	c.openedAt = time.Now()
+	c.successes, c.failures = 0, 0
+}
+
+func (c *circuit) reset() {
+	c.state = closedState
+	c.successes, c.failures = 0, 0
+	c.windowAt = time.Now()
+}
+
+// rollWindow clears accumulated counts once Window has elapsed so a
+// closed breaker's error rate reflects only recent traffic.
+func (c *circuit) rollWindow() {
+	if c.state != closedState {
+		return
+	}
+	if time.Since(c.windowAt) >= c.cfg.Window {
+		c.successes, c.failures = 0, 0
+		c.windowAt = time.Now()
+	}
+}
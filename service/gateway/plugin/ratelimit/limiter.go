@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Limiter decides whether a request may proceed, returning how long the
+// caller should wait before retrying when it may not.
+type Limiter interface {
+	Allow(r *http.Request) (bool, time.Duration)
+}
+
+func loadConfig(path string) (*Config, error) {
+	if len(path) == 0 {
+		return &Config{}, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// keyFor resolves the bucket key for r under rule, per its KeyBy selector.
+func keyFor(rule Rule, r *http.Request) string {
+	switch {
+	case rule.KeyBy == "endpoint":
+		return r.Method + " " + r.URL.Path
+	case strings.HasPrefix(rule.KeyBy, "header:"):
+		name := strings.TrimPrefix(rule.KeyBy, "header:")
+		return r.Header.Get(name)
+	default:
+		host := r.RemoteAddr
+		if i := strings.LastIndexByte(host, ':'); i >= 0 {
+			host = host[:i]
+		}
+		return host
+	}
+}
+
+// matches reports whether rule applies to r.
+func matches(rule Rule, r *http.Request) bool {
+	if len(rule.Path) > 0 && !strings.HasPrefix(r.URL.Path, rule.Path) {
+		return false
+	}
+	return true
+}
+
+// bucket is a classic token bucket: it holds up to burst tokens,
+// refilling at rate tokens per `per`, and is safe for concurrent use.
+type bucket struct {
+	sync.Mutex
+
+	rate  int
+	per   time.Duration
+	burst int
+
+	tokens   float64
+	lastFill time.Time
+}
+
+func newBucket(rule Rule) *bucket {
+	burst := rule.Burst
+	if burst <= 0 {
+		burst = rule.Rate
+	}
+	return &bucket{
+		rate:     rule.Rate,
+		per:      rule.Per,
+		burst:    burst,
+		tokens:   float64(burst),
+		lastFill: time.Now(),
+	}
+}
+
+func (b *bucket) take() (bool, time.Duration) {
+	b.Lock()
+	defer b.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(b.lastFill)
+	refill := elapsed.Seconds() / b.per.Seconds() * float64(b.rate)
+	b.tokens += refill
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+	b.lastFill = now
+
+	if b.tokens < 1 {
+		wait := b.per / time.Duration(b.rate)
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// memoryLimiter is the single-instance, in-process token bucket backend.
+type memoryLimiter struct {
+	rules []Rule
+
+	sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newMemoryLimiter(rules []Rule) *memoryLimiter {
+	return &memoryLimiter{rules: rules, buckets: map[string]*bucket{}}
+}
+
+func (m *memoryLimiter) Allow(r *http.Request) (bool, time.Duration) {
+	for _, rule := range m.rules {
+		if !matches(rule, r) {
+			continue
+		}
+
+		key := rule.Path + "|" + keyFor(rule, r)
+		m.Lock()
+		b, ok := m.buckets[key]
+		if !ok {
+			b = newBucket(rule)
+			m.buckets[key] = b
+		}
+		m.Unlock()
+
+		return b.take()
+	}
+	return true, 0
+}
@@ -0,0 +1,110 @@
+// Package ratelimit is a gateway plugin enforcing a rate limit in front
+// of the backends, keyed by client IP, a request header, or the inbound
+// method+path. The service+endpoint a request resolves to is not known
+// to it (see Rule). It ships with an in-memory token-bucket backend for
+// single-instance gateways and a Redis fixed-window backend for rate
+// limits shared across a gateway cluster.
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/stack-labs/stack-rpc/pkg/cli"
+	"github.com/stack-labs/stack-rpc/util/log"
+
+	"github.com/stack-labs/stack-rpc-plugins/service/gateway/plugin"
+)
+
+func init() {
+	plugin.Register(newPlugin())
+}
+
+// Config is the rate limit file format; its rules are scoped by path
+// prefix only, not by service or namespace.
+type Config struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule matches requests by path prefix and caps them at Rate tokens per
+// Per, refilling one bucket per KeyBy value. Matching is path-only: the
+// gateway's rate limit plugin runs ahead of service resolution (it wraps
+// the whole mux router, not the resolved per-call handler), so there is
+// no resolved namespace/service to scope a rule against.
+type Rule struct {
+	Path  string        `yaml:"path"`
+	KeyBy string        `yaml:"key_by"` // "ip", "header:<Name>", or "endpoint"
+	Rate  int           `yaml:"rate"`
+	Per   time.Duration `yaml:"per"`
+	Burst int           `yaml:"burst"`
+}
+
+type ratelimitPlugin struct {
+	enabled bool
+	cfgPath string
+	limiter Limiter
+}
+
+func newPlugin() *ratelimitPlugin {
+	return &ratelimitPlugin{}
+}
+
+func (p *ratelimitPlugin) Flags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:   "enable_ratelimit",
+			Usage:  "Enable token-bucket rate limiting in front of backends",
+			EnvVar: "MICRO_API_ENABLE_RATELIMIT",
+		},
+		cli.StringFlag{
+			Name:   "ratelimit_config",
+			Usage:  "Path to the rate limit rule file e.g /etc/gateway/ratelimit.yaml",
+			EnvVar: "MICRO_API_RATELIMIT_CONFIG",
+		},
+		cli.StringFlag{
+			Name:   "ratelimit_redis_address",
+			Usage:  "Redis address used as the shared rate limit backend; in-memory if unset",
+			EnvVar: "MICRO_API_RATELIMIT_REDIS_ADDRESS",
+		},
+	}
+}
+
+func (p *ratelimitPlugin) Init(ctx *cli.Context) {
+	p.enabled = ctx.GlobalBool("enable_ratelimit")
+	if !p.enabled {
+		return
+	}
+
+	p.cfgPath = ctx.GlobalString("ratelimit_config")
+	cfg, err := loadConfig(p.cfgPath)
+	if err != nil {
+		log.Fatalf("ratelimit: failed to load %s: %v", p.cfgPath, err)
+	}
+
+	if addr := ctx.GlobalString("ratelimit_redis_address"); len(addr) > 0 {
+		p.limiter = newRedisLimiter(addr, cfg.Rules)
+	} else {
+		p.limiter = newMemoryLimiter(cfg.Rules)
+	}
+}
+
+func (p *ratelimitPlugin) Handler() plugin.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !p.enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allow, retryAfter := p.limiter.Allow(r)
+			if !allow {
+				w.Header().Set("Retry-After", fmt.Sprintf("%.0f", retryAfter.Seconds()))
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/stack-labs/stack-rpc/util/log"
+)
+
+// redisLimiter counts requests in Redis via INCR+TTL so the limit is
+// shared across every gateway instance behind the same cache, rather
+// than per-process like memoryLimiter. It is a fixed-window counter, not
+// a token bucket: each key allows at most Rate requests per Per window,
+// and Burst is not applied.
+type redisLimiter struct {
+	rules []Rule
+	rdb   *redis.Client
+}
+
+func newRedisLimiter(addr string, rules []Rule) *redisLimiter {
+	return &redisLimiter{
+		rules: rules,
+		rdb:   redis.NewClient(&redis.Options{Addr: addr}),
+	}
+}
+
+func (rl *redisLimiter) Allow(r *http.Request) (bool, time.Duration) {
+	for _, rule := range rl.rules {
+		if !matches(rule, r) {
+			continue
+		}
+		return rl.take(rule, r)
+	}
+	return true, 0
+}
+
+func (rl *redisLimiter) take(rule Rule, r *http.Request) (bool, time.Duration) {
+	ctx := r.Context()
+	key := "stack.gateway.ratelimit:" + rule.Path + "|" + keyFor(rule, r)
+
+	count, err := rl.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		log.Logf("ratelimit: redis error, failing open: %v", err)
+		return true, 0
+	}
+	if count == 1 {
+		rl.rdb.Expire(ctx, key, rule.Per)
+	}
+
+	if int(count) > rule.Rate {
+		ttl, err := rl.rdb.TTL(ctx, key).Result()
+		if err != nil || ttl < 0 {
+			ttl = rule.Per
+		}
+		return false, ttl
+	}
+
+	return true, 0
+}
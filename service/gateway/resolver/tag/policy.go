@@ -0,0 +1,89 @@
+package tag
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Policy is the tag-rule file format: an ordered list of Match rules
+// evaluated top to bottom, falling back to a weighted random split when
+// no rule matches (or when there are no rules at all).
+type Policy struct {
+	Rules   []MatchRule    `yaml:"match"`
+	Weights map[string]int `yaml:"weight"`
+}
+
+// MatchRule selects Tag when Header, Cookie or Query (whichever is set)
+// equals Value, compared case-insensitively, for the inbound request.
+type MatchRule struct {
+	Header string `yaml:"header"`
+	Cookie string `yaml:"cookie"`
+	Query  string `yaml:"query"`
+	Value  string `yaml:"value"`
+	Tag    string `yaml:"tag"`
+}
+
+func loadPolicy(path string) (*Policy, error) {
+	if len(path) == 0 {
+		return &Policy{}, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := yaml.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// resolveTag evaluates p against r and returns the chosen tag, or ""
+// for "use whatever the registry returns with no preference".
+func (p *Policy) resolveTag(r *http.Request) string {
+	for _, rule := range p.Rules {
+		var got string
+		switch {
+		case len(rule.Header) > 0:
+			got = r.Header.Get(rule.Header)
+		case len(rule.Cookie) > 0:
+			if c, err := r.Cookie(rule.Cookie); err == nil {
+				got = c.Value
+			}
+		case len(rule.Query) > 0:
+			got = r.URL.Query().Get(rule.Query)
+		}
+
+		if strings.EqualFold(got, rule.Value) {
+			return rule.Tag
+		}
+	}
+
+	return p.weightedTag()
+}
+
+// weightedTag picks a tag at random, proportional to its configured
+// weight, so e.g. weight: v1=90,v2=10 sends ~10% of otherwise-unmatched
+// traffic to v2.
+func (p *Policy) weightedTag() string {
+	total := 0
+	for _, w := range p.Weights {
+		total += w
+	}
+	if total <= 0 {
+		return ""
+	}
+
+	n := rand.Intn(total)
+	for tag, w := range p.Weights {
+		if n < w {
+			return tag
+		}
+		n -= w
+	}
+	return ""
+}
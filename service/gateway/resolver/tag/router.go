@@ -0,0 +1,99 @@
+package tag
+
+import (
+	"net/http"
+
+	"github.com/stack-labs/stack-rpc/api"
+	"github.com/stack-labs/stack-rpc/api/resolver"
+	"github.com/stack-labs/stack-rpc/api/router"
+	"github.com/stack-labs/stack-rpc/registry"
+)
+
+// tagSetter evaluates the tag policy against a request and records the
+// result as TagHeader. *tagResolver implements it.
+type tagSetter interface {
+	SetTag(r *http.Request)
+}
+
+// NewRouter wraps next so that once the tag resolver has recorded a tag
+// for a request (TagHeader), only the registry nodes matching it are
+// left in the returned api.Service. Without this the resolver only ever
+// decorates the request with a header - the rpc handler's selector
+// picks at random among whatever nodes the router hands it, so the
+// filtering has to happen here for canary/A-B routing to take effect.
+//
+// rr is the resolver.Resolver built alongside next (tagResolver.NewResolver's
+// return value): tagRouter calls its SetTag directly rather than relying
+// on next.Route() to invoke Resolve(), because the registry router skips
+// Resolve() entirely for services registered via explicit host/method/path
+// registry metadata (handler=api) whose internal Endpoint() table lookup
+// already matches.
+func NewRouter(next router.Router, rr resolver.Resolver) router.Router {
+	ts, _ := rr.(tagSetter)
+	return &tagRouter{Router: next, tag: ts}
+}
+
+type tagRouter struct {
+	router.Router
+	tag tagSetter
+}
+
+func (tr *tagRouter) Route(r *http.Request) (*api.Service, error) {
+	if tr.tag != nil {
+		tr.tag.SetTag(r)
+	}
+	s, err := tr.Router.Route(r)
+	if err != nil {
+		return nil, err
+	}
+	return filterByTag(s, r.Header.Get(TagHeader)), nil
+}
+
+func (tr *tagRouter) Endpoint(r *http.Request) (*api.Service, error) {
+	if tr.tag != nil {
+		tr.tag.SetTag(r)
+	}
+	s, err := tr.Router.Endpoint(r)
+	if err != nil {
+		return nil, err
+	}
+	return filterByTag(s, r.Header.Get(TagHeader)), nil
+}
+
+// filterByTag narrows s.Services down to the nodes whose Metadata
+// "version" or "env" equals tag, matching the doc'd canary (env=canary)
+// and A-B (version=v2) conventions. An empty tag, or a tag that matches
+// nothing, leaves s untouched rather than erroring the request out -
+// better to fall back to the full untagged node set than to 5xx.
+func filterByTag(s *api.Service, tag string) *api.Service {
+	if s == nil || len(tag) == 0 {
+		return s
+	}
+
+	var filtered []*registry.Service
+	for _, svc := range s.Services {
+		var nodes []*registry.Node
+		for _, n := range svc.Nodes {
+			if n.Metadata == nil {
+				continue
+			}
+			if n.Metadata["version"] == tag || n.Metadata["env"] == tag {
+				nodes = append(nodes, n)
+			}
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+		cp := *svc
+		cp.Nodes = nodes
+		filtered = append(filtered, &cp)
+	}
+
+	if len(filtered) == 0 {
+		return s
+	}
+
+	out := *s
+	out.Services = filtered
+	return &out
+}
@@ -0,0 +1,97 @@
+// Package tag implements an api/resolver.Resolver for progressive
+// delivery: it resolves the backend service exactly as the stack
+// resolver does, then evaluates a rule/weight policy against the
+// request (header, cookie, query param, or weighted split) to pick a
+// version/environment tag, and records it on the request so the
+// registry node selection further down the chain can filter nodes by
+// matching Metadata (`version=v2`, `env=canary`) - the service name
+// itself never changes.
+package tag
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/stack-labs/stack-rpc/api/resolver"
+	rrstack "github.com/stack-labs/stack-rpc/api/resolver/stack"
+	"github.com/stack-labs/stack-rpc/util/log"
+)
+
+// TagHeader is set on the request before it reaches the router/selector,
+// carrying the policy's decision for this call.
+const TagHeader = "X-Stack-Tag"
+
+type tagResolver struct {
+	next resolver.Resolver
+
+	sync.RWMutex
+	policy *Policy
+	watch  *watcher
+}
+
+// NewResolver wraps the stack resolver with tag-based policy evaluation.
+// rules is the path to the policy file named by --tag_rules; an empty
+// path falls back to resolving with no tag preference.
+func NewResolver(rules string, opts ...resolver.Option) resolver.Resolver {
+	tr := &tagResolver{next: rrstack.NewResolver(opts...)}
+
+	if len(rules) == 0 {
+		tr.policy = &Policy{}
+		return tr
+	}
+
+	p, err := loadPolicy(rules)
+	if err != nil {
+		log.Fatalf("resolver/tag: failed to load %s: %v", rules, err)
+	}
+	tr.policy = p
+
+	w, err := newWatcher(rules, tr.setPolicy)
+	if err != nil {
+		log.Fatalf("resolver/tag: failed to watch %s: %v", rules, err)
+	}
+	tr.watch = w
+
+	return tr
+}
+
+func (tr *tagResolver) setPolicy(p *Policy) {
+	tr.Lock()
+	defer tr.Unlock()
+	tr.policy = p
+}
+
+func (tr *tagResolver) Resolve(r *http.Request) (*resolver.Endpoint, error) {
+	ep, err := tr.next.Resolve(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tr.SetTag(r)
+
+	return ep, nil
+}
+
+// SetTag evaluates the policy against r and records the result as
+// TagHeader, same as Resolve does as a side effect of resolving. It is
+// exported separately because the registry router's Route() skips
+// calling Resolve at all for services registered via explicit
+// host/method/path registry metadata (the handler=api convention) - its
+// own Endpoint() table lookup matches first and short-circuits the
+// fallback to Resolver.Resolve(). tagRouter calls this directly so the
+// tag still gets set for those services.
+func (tr *tagResolver) SetTag(r *http.Request) {
+	tr.RLock()
+	tag := tr.policy.resolveTag(r)
+	tr.RUnlock()
+
+	if len(tag) > 0 {
+		r.Header.Set(TagHeader, tag)
+	} else {
+		r.Header.Del(TagHeader)
+	}
+}
+
+func (tr *tagResolver) String() string {
+	return "tag"
+}
@@ -0,0 +1,41 @@
+package tag
+
+import (
+	"github.com/stack-labs/stack-rpc/util/log"
+
+	"github.com/stack-labs/stack-rpc-plugins/service/gateway/fswatch"
+)
+
+// watcher reloads the tag policy whenever its backing file changes, so
+// canary rollouts can be adjusted without restarting the gateway.
+type watcher struct {
+	path string
+	fw   *fswatch.Watcher
+}
+
+func newWatcher(path string, onChange func(*Policy)) (*watcher, error) {
+	w := &watcher{path: path}
+
+	fw, err := fswatch.New(path, func() { w.reload(onChange) }, func(err error) {
+		log.Logf("resolver/tag: watch error: %v", err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.fw = fw
+	return w, nil
+}
+
+func (w *watcher) reload(onChange func(*Policy)) {
+	p, err := loadPolicy(w.path)
+	if err != nil {
+		log.Logf("resolver/tag: failed to reload %s: %v", w.path, err)
+		return
+	}
+	log.Logf("resolver/tag: reloaded policy from %s", w.path)
+	onChange(p)
+}
+
+func (w *watcher) Close() error {
+	return w.fw.Close()
+}
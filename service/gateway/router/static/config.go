@@ -0,0 +1,126 @@
+package static
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/stack-labs/stack-rpc/util/log"
+
+	"github.com/stack-labs/stack-rpc-plugins/service/gateway/fswatch"
+)
+
+// Route is a single declarative mapping from an inbound HTTP request to a
+// backend service+endpoint. Path supports gorilla-mux style `{var}`
+// placeholders which are matched but otherwise ignored by the router -
+// the whole path is forwarded to the backend as-is.
+type Route struct {
+	Host    string            `yaml:"host"`
+	Method  string            `yaml:"method"`
+	Path    string            `yaml:"path"`
+	Service string            `yaml:"service"`
+	Verb    string            `yaml:"endpoint"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+
+	pattern *regexp.Regexp
+}
+
+// Table is the top level route file format.
+type Table struct {
+	Routes []*Route `yaml:"routes"`
+}
+
+// compile turns the `{var}` path template into a matching regexp, mirroring
+// the placeholder syntax gorilla/mux already uses elsewhere in the gateway.
+func (r *Route) compile() error {
+	var buf strings.Builder
+	buf.WriteString("^")
+
+	i := 0
+	for i < len(r.Path) {
+		c := r.Path[i]
+		if c == '{' {
+			end := strings.IndexByte(r.Path[i:], '}')
+			if end < 0 {
+				return fmt.Errorf("unterminated path variable in %q", r.Path)
+			}
+			buf.WriteString("[^/]+")
+			i += end + 1
+			continue
+		}
+		buf.WriteString(regexp.QuoteMeta(string(c)))
+		i++
+	}
+	buf.WriteString("$")
+
+	pattern, err := regexp.Compile(buf.String())
+	if err != nil {
+		return err
+	}
+	r.pattern = pattern
+	return nil
+}
+
+// loadTable reads and parses the route file at path.
+func loadTable(path string) (*Table, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var t Table
+	if err := yaml.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse router config %s: %v", path, err)
+	}
+
+	for _, route := range t.Routes {
+		if len(route.Method) == 0 {
+			route.Method = "*"
+		}
+		if err := route.compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &t, nil
+}
+
+// watcher hot-reloads the route table whenever the backing file changes on
+// disk, swapping it into the router without restarting the gateway.
+type watcher struct {
+	path     string
+	fw       *fswatch.Watcher
+	onChange func(*Table)
+}
+
+func newWatcher(path string, onChange func(*Table)) (*watcher, error) {
+	w := &watcher{path: path, onChange: onChange}
+
+	fw, err := fswatch.New(path, w.reload, func(err error) {
+		log.Logf("router/static: watch error: %v", err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.fw = fw
+	return w, nil
+}
+
+func (w *watcher) reload() {
+	t, err := loadTable(w.path)
+	if err != nil {
+		log.Logf("router/static: failed to reload %s: %v", w.path, err)
+		return
+	}
+	log.Logf("router/static: reloaded route table from %s", w.path)
+	w.onChange(t)
+}
+
+func (w *watcher) Close() error {
+	return w.fw.Close()
+}
@@ -0,0 +1,141 @@
+// Package static provides a file-based api/router.Router implementation.
+//
+// Unlike the registry router, routes are not discovered from services
+// registered against a registry - they are declared up front in a YAML
+// file and hot-reloaded on change. This lets a gateway be deployed with a
+// pinned route->service mapping where operators want exact control over
+// the exposed surface. The nodes of each route's backend service are
+// still looked up in the registry, so a request fails when the registry
+// is unreachable.
+package static
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/stack-labs/stack-rpc/api"
+	"github.com/stack-labs/stack-rpc/api/router"
+	"github.com/stack-labs/stack-rpc/registry"
+	"github.com/stack-labs/stack-rpc/util/log"
+)
+
+// staticRouter implements router.Router by matching incoming requests
+// against a declarative route table loaded from disk.
+type staticRouter struct {
+	opts router.Options
+
+	path string
+
+	sync.RWMutex
+	routes []*Route
+	watch  *watcher
+}
+
+// NewRouter returns a router.Router backed by the route file named by
+// config. The file is watched for changes and reloaded automatically.
+func NewRouter(config string, opts ...router.Option) router.Router {
+	sr := &staticRouter{
+		opts: router.NewOptions(opts...),
+		path: config,
+	}
+
+	t, err := loadTable(config)
+	if err != nil {
+		log.Fatalf("router/static: %v", err)
+	}
+	sr.routes = t.Routes
+
+	w, err := newWatcher(config, sr.setRoutes)
+	if err != nil {
+		log.Fatalf("router/static: failed to watch %s: %v", config, err)
+	}
+	sr.watch = w
+
+	return sr
+}
+
+func (sr *staticRouter) setRoutes(t *Table) {
+	sr.Lock()
+	defer sr.Unlock()
+	sr.routes = t.Routes
+}
+
+func (sr *staticRouter) Options() router.Options {
+	return sr.opts
+}
+
+func (sr *staticRouter) Close() error {
+	if sr.watch != nil {
+		return sr.watch.Close()
+	}
+	return nil
+}
+
+// Endpoint matches r against the configured route table, applies any
+// headers the route declares, and resolves it to a backend service
+// endpoint - the same job the registry router's Endpoint does for
+// routes discovered dynamically.
+func (sr *staticRouter) Endpoint(r *http.Request) (*api.Service, error) {
+	sr.RLock()
+	defer sr.RUnlock()
+
+	for _, rt := range sr.routes {
+		if len(rt.Host) > 0 && rt.Host != r.Host {
+			continue
+		}
+		if rt.Method != "*" && rt.Method != r.Method {
+			continue
+		}
+		if !rt.pattern.MatchString(r.URL.Path) {
+			continue
+		}
+
+		for k, v := range rt.Headers {
+			r.Header.Set(k, v)
+		}
+
+		services, err := sr.opts.Registry.GetService(rt.Service)
+		if err != nil {
+			return nil, err
+		}
+
+		return &api.Service{
+			Name: rt.Service,
+			Endpoint: &api.Endpoint{
+				Name:    rt.Verb,
+				Handler: sr.opts.Handler,
+			},
+			Services: services,
+		}, nil
+	}
+
+	return nil, registry.ErrNotFound
+}
+
+// Route tries a static route match first, falling back to the
+// configured resolver so a gateway running --router=static can still
+// resolve calls with no declared route (e.g. /rpc passthrough).
+func (sr *staticRouter) Route(r *http.Request) (*api.Service, error) {
+	if ep, err := sr.Endpoint(r); err == nil {
+		return ep, nil
+	}
+
+	rp, err := sr.opts.Resolver.Resolve(r)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := sr.opts.Registry.GetService(rp.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Service{
+		Name: rp.Name,
+		Endpoint: &api.Endpoint{
+			Name:    rp.Method,
+			Handler: sr.opts.Handler,
+		},
+		Services: services,
+	}, nil
+}
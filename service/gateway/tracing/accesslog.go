@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/stack-labs/stack-rpc/util/log"
+)
+
+// accessLog writes one JSON line per sampled request, independent of
+// whether that request's span was itself sampled by the trace exporter.
+type accessLog struct {
+	sampleRate float64
+}
+
+func newAccessLog(sampleRate float64) *accessLog {
+	if sampleRate <= 0 {
+		sampleRate = 1.0
+	}
+	return &accessLog{sampleRate: sampleRate}
+}
+
+type accessLogEntry struct {
+	Time      string `json:"time"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Status    int    `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	TraceID   string `json:"trace_id"`
+	SpanID    string `json:"span_id"`
+}
+
+func (a *accessLog) Write(r *http.Request, status int, latency time.Duration, sc SpanContext) {
+	if a.sampleRate < 1.0 && rand.Float64() > a.sampleRate {
+		return
+	}
+
+	entry := accessLogEntry{
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Status:    status,
+		LatencyMs: latency.Milliseconds(),
+		TraceID:   sc.TraceID,
+		SpanID:    sc.SpanID,
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		log.Logf("tracing: failed to marshal access log entry: %v", err)
+		return
+	}
+	log.Logf("%s", b)
+}
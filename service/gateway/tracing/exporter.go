@@ -0,0 +1,131 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/stack-labs/stack-rpc/util/log"
+)
+
+// Exporter ships completed spans to a tracing backend.
+type Exporter interface {
+	Export(span *Span)
+}
+
+func newExporter(kind, endpoint string) (Exporter, error) {
+	switch kind {
+	case "", "otlp":
+		return &otlpExporter{endpoint: endpoint, client: &http.Client{Timeout: 5 * time.Second}}, nil
+	case "zipkin":
+		return &zipkinExporter{endpoint: endpoint, client: &http.Client{Timeout: 5 * time.Second}}, nil
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", kind)
+	}
+}
+
+// otlpExporter posts spans as OTLP/HTTP JSON to a collector.
+type otlpExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (e *otlpExporter) Export(span *Span) {
+	if len(e.endpoint) == 0 {
+		return
+	}
+	go e.send(span)
+}
+
+func (e *otlpExporter) send(span *Span) {
+	body, err := json.Marshal(otlpSpan{
+		TraceID:           span.TraceID,
+		SpanID:            span.SpanID,
+		Name:              span.Handler,
+		StartTimeUnixNano: span.StartTime.UnixNano(),
+		EndTimeUnixNano:   span.StartTime.Add(span.Latency).UnixNano(),
+		Attributes: map[string]interface{}{
+			"http.method":       span.Method,
+			"http.target":       span.Path,
+			"http.status_code":  span.ResponseCode,
+			"gateway.handler":   span.Handler,
+			"gateway.namespace": span.Namespace,
+			"gateway.service":   span.Service,
+			"gateway.endpoint":  span.Endpoint,
+		},
+	})
+	if err != nil {
+		log.Logf("tracing: failed to marshal otlp span: %v", err)
+		return
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Logf("tracing: failed to export span: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+type otlpSpan struct {
+	TraceID           string                 `json:"traceId"`
+	SpanID            string                 `json:"spanId"`
+	Name              string                 `json:"name"`
+	StartTimeUnixNano int64                  `json:"startTimeUnixNano"`
+	EndTimeUnixNano   int64                  `json:"endTimeUnixNano"`
+	Attributes        map[string]interface{} `json:"attributes"`
+}
+
+// zipkinExporter posts spans in the Zipkin v2 JSON span format.
+type zipkinExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (e *zipkinExporter) Export(span *Span) {
+	if len(e.endpoint) == 0 {
+		return
+	}
+	go e.send(span)
+}
+
+func (e *zipkinExporter) send(span *Span) {
+	body, err := json.Marshal([]zipkinSpan{{
+		TraceID:        span.TraceID,
+		ID:             span.SpanID,
+		Name:           span.Handler,
+		Timestamp:      span.StartTime.UnixNano() / int64(time.Microsecond),
+		DurationMicros: span.Latency.Microseconds(),
+		Tags: map[string]string{
+			"http.method":       span.Method,
+			"http.path":         span.Path,
+			"http.status_code":  fmt.Sprintf("%d", span.ResponseCode),
+			"gateway.handler":   span.Handler,
+			"gateway.namespace": span.Namespace,
+			"gateway.service":   span.Service,
+			"gateway.endpoint":  span.Endpoint,
+		},
+	}})
+	if err != nil {
+		log.Logf("tracing: failed to marshal zipkin span: %v", err)
+		return
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Logf("tracing: failed to export span: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+type zipkinSpan struct {
+	TraceID        string            `json:"traceId"`
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Timestamp      int64             `json:"timestamp"`
+	DurationMicros int64             `json:"duration"`
+	Tags           map[string]string `json:"tags"`
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/stack-labs/stack-rpc/api"
+	"github.com/stack-labs/stack-rpc/api/router"
+)
+
+// routeInfo is a mutable box stashed on the request context by the
+// tracing plugin: NewRouter fills it in once the chosen router.Router
+// has resolved a backend, so the span/access-log can record what the
+// request actually routed to rather than just the raw inbound path.
+type routeInfo struct {
+	Service  string
+	Endpoint string
+}
+
+type routeInfoKey struct{}
+
+// withRouteInfo attaches an empty routeInfo box to ctx and returns it
+// alongside the new context, so the caller can read it back after the
+// request has been routed and served.
+func withRouteInfo(ctx context.Context) (context.Context, *routeInfo) {
+	info := &routeInfo{}
+	return context.WithValue(ctx, routeInfoKey{}, info), info
+}
+
+func routeInfoFromContext(ctx context.Context) (*routeInfo, bool) {
+	info, ok := ctx.Value(routeInfoKey{}).(*routeInfo)
+	return info, ok
+}
+
+// NewRouter wraps next so that whichever api.Service it resolves a
+// request to gets recorded in that request's routeInfo box. It is safe
+// to wrap every router with this regardless of whether tracing is
+// enabled - recording is a no-op when the request has no routeInfo box.
+func NewRouter(next router.Router) router.Router {
+	return &routingRouter{Router: next}
+}
+
+type routingRouter struct {
+	router.Router
+}
+
+func (rr *routingRouter) Route(r *http.Request) (*api.Service, error) {
+	s, err := rr.Router.Route(r)
+	if err == nil {
+		record(r, s)
+	}
+	return s, err
+}
+
+func (rr *routingRouter) Endpoint(r *http.Request) (*api.Service, error) {
+	s, err := rr.Router.Endpoint(r)
+	if err == nil {
+		record(r, s)
+	}
+	return s, err
+}
+
+func record(r *http.Request, s *api.Service) {
+	info, ok := routeInfoFromContext(r.Context())
+	if !ok {
+		return
+	}
+	info.Service = s.Name
+	if s.Endpoint != nil {
+		info.Endpoint = s.Endpoint.Name
+	}
+}
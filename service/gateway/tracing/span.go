@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SpanContext is the W3C trace context for one request, parsed from an
+// inbound `traceparent` header or freshly minted when absent.
+type SpanContext struct {
+	TraceID    string
+	SpanID     string
+	Sampled    bool
+	TraceState string
+}
+
+// Traceparent renders the context back out as a W3C traceparent header
+// value, to forward to the backend service.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// Span is one gateway hop, handed to the configured Exporter once the
+// request completes.
+type Span struct {
+	SpanContext
+	Namespace    string
+	Service      string
+	Endpoint     string
+	Handler      string
+	Path         string
+	Method       string
+	ResponseCode int
+	Latency      time.Duration
+	StartTime    time.Time
+}
+
+// startSpan parses an inbound traceparent/B3 header or starts a new root
+// span, and returns the gateway's own span id as the new parent for the
+// downstream call.
+func startSpan(r *http.Request) (SpanContext, *Span) {
+	sc, ok := parseTraceparent(r.Header.Get("Traceparent"))
+	if !ok {
+		sc, ok = parseB3(r)
+	}
+	if !ok {
+		sc = SpanContext{TraceID: newID(16), Sampled: true}
+	}
+	sc.TraceState = r.Header.Get("Tracestate")
+
+	// this gateway hop gets its own span id, parented under whatever
+	// trace id we resolved above.
+	sc.SpanID = newID(8)
+
+	span := &Span{
+		SpanContext: sc,
+		Path:        r.URL.Path,
+		Method:      r.Method,
+		StartTime:   time.Now(),
+	}
+	return sc, span
+}
+
+func parseTraceparent(h string) (SpanContext, bool) {
+	parts := strings.Split(h, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return SpanContext{}, false
+	}
+	return SpanContext{
+		TraceID: parts[1],
+		SpanID:  parts[2],
+		Sampled: parts[3] == "01",
+	}, true
+}
+
+func parseB3(r *http.Request) (SpanContext, bool) {
+	traceID := r.Header.Get("X-B3-Traceid")
+	if len(traceID) == 0 {
+		return SpanContext{}, false
+	}
+	return SpanContext{
+		TraceID: traceID,
+		SpanID:  r.Header.Get("X-B3-Spanid"),
+		Sampled: r.Header.Get("X-B3-Sampled") == "1",
+	}, true
+}
+
+func newID(bytes int) string {
+	b := make([]byte, bytes)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,141 @@
+// Package tracing starts a span per inbound gateway request, propagates
+// it to the backend stack-rpc service, and records a per-hop JSON
+// access-log line. It plugs into the same plugin.Plugins() chain as the
+// rate limiter and circuit breaker, so it applies uniformly across every
+// handler mode (rpc/api/event/http/web/meta).
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/stack-labs/stack-rpc/pkg/cli"
+	"github.com/stack-labs/stack-rpc/pkg/metadata"
+	"github.com/stack-labs/stack-rpc/util/log"
+
+	"github.com/stack-labs/stack-rpc-plugins/service/gateway/plugin"
+)
+
+func init() {
+	plugin.Register(newPlugin())
+}
+
+type tracingPlugin struct {
+	enabled   bool
+	handler   string
+	namespace string
+	exporter  Exporter
+	access    *accessLog
+}
+
+func newPlugin() *tracingPlugin {
+	return &tracingPlugin{}
+}
+
+func (p *tracingPlugin) Flags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:   "enable_tracing",
+			Usage:  "Enable request tracing and the JSON access log",
+			EnvVar: "MICRO_API_ENABLE_TRACING",
+		},
+		cli.StringFlag{
+			Name:   "tracing_exporter",
+			Usage:  "Trace exporter to use {otlp, zipkin}",
+			EnvVar: "MICRO_API_TRACING_EXPORTER",
+		},
+		cli.StringFlag{
+			Name:   "tracing_endpoint",
+			Usage:  "Collector endpoint for the chosen trace exporter",
+			EnvVar: "MICRO_API_TRACING_ENDPOINT",
+		},
+		cli.Float64Flag{
+			Name:   "access_log_sample_rate",
+			Usage:  "Fraction of requests (0.0-1.0) written to the JSON access log; 0 or less writes every request",
+			Value:  1.0,
+			EnvVar: "MICRO_API_ACCESS_LOG_SAMPLE_RATE",
+		},
+	}
+}
+
+func (p *tracingPlugin) Init(ctx *cli.Context) {
+	p.enabled = ctx.GlobalBool("enable_tracing")
+	if !p.enabled {
+		return
+	}
+
+	exp, err := newExporter(ctx.GlobalString("tracing_exporter"), ctx.GlobalString("tracing_endpoint"))
+	if err != nil {
+		log.Fatalf("tracing: failed to init exporter: %v", err)
+	}
+	p.exporter = exp
+	p.access = newAccessLog(ctx.GlobalFloat64("access_log_sample_rate"))
+	p.handler = ctx.String("handler")
+	p.namespace = ctx.String("namespace")
+}
+
+func (p *tracingPlugin) Handler() plugin.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !p.enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			spanCtx, span := startSpan(r)
+			ctx := withDownstreamMetadata(r.Context(), spanCtx)
+			ctx, info := withRouteInfo(ctx)
+			r = r.WithContext(ctx)
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rec, r)
+			latency := time.Since(start)
+
+			span.ResponseCode = rec.status
+			span.Handler = p.handler
+			span.Namespace = p.namespace
+			span.Service = info.Service
+			span.Endpoint = info.Endpoint
+			span.Latency = latency
+			p.exporter.Export(span)
+			p.access.Write(r, rec.status, latency, spanCtx)
+		})
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// withDownstreamMetadata stashes the trace context into the stack-rpc
+// metadata so it rides along on the client call the resolved handler
+// makes to the backend service.
+func withDownstreamMetadata(ctx context.Context, sc SpanContext) context.Context {
+	md, ok := metadata.FromContext(ctx)
+	if !ok {
+		md = metadata.Metadata{}
+	}
+	md["Traceparent"] = sc.Traceparent()
+	if len(sc.TraceState) > 0 {
+		md["Tracestate"] = sc.TraceState
+	}
+	md["X-B3-Traceid"] = sc.TraceID
+	md["X-B3-Spanid"] = sc.SpanID
+	md["X-B3-Sampled"] = b3Sampled(sc.Sampled)
+	return metadata.NewContext(ctx, md)
+}
+
+func b3Sampled(sampled bool) string {
+	if sampled {
+		return "1"
+	}
+	return "0"
+}